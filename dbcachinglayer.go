@@ -1,17 +1,55 @@
 package dbcachinglayer
 
 import (
+	"cmp"
+	"container/list"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// journalFileName is the name of the write-ahead journal file DBCL keeps
+// inside its journal directory. See WithJournalDir and CloseContext.
+const journalFileName = "writecache.journal"
+
+// Logger lets callers plug in their own structured logger (zap, zerolog,
+// ...) in place of the default log.Printf-based one. See WithLogger.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// stdLogger adapts the standard library "log" package to the Logger
+// interface. It is the default used when no WithLogger option is given.
+type stdLogger struct{}
+
+func (stdLogger) Debugf(format string, args ...any) { log.Printf(format, args...) }
+func (stdLogger) Infof(format string, args ...any)  { log.Printf(format, args...) }
+func (stdLogger) Errorf(format string, args ...any) { log.Printf(format, args...) }
+
+// flushDurationBucketBoundsMs are the upper bounds (inclusive, milliseconds)
+// of the histogram buckets tracked in Stats.FlushDurationBuckets. A flush
+// slower than the last bound falls into the overflow bucket.
+var flushDurationBucketBoundsMs = []int64{1, 5, 10, 50, 100, 500, 1000, 5000}
+
 type DBCLRecord interface {
 	DBCLNewRecord(...interface{}) DBCLRecord
 	DBCLSelectAll(*sql.DB) (*sql.Rows, error)
+	DBCLSelectIDs(*sql.DB) (*sql.Rows, error)
+	DBCLSelectByID(*sql.DB, int64) (*sql.Rows, error)
+	DBCLSelectRange(*sql.DB, int64, int64) (*sql.Rows, error)
 	DBCLInsert(*sql.Tx, DBCLRecord) (sql.Result, error)
 	DBCLUpdate(*sql.Tx, DBCLRecord) (sql.Result, error)
 	DBCLDelete(*sql.Tx, int64) (sql.Result, error)
@@ -21,6 +59,274 @@ type DBCLRecord interface {
 	DBCLSetId(int64)
 }
 
+// DBCLBulkInserter is an optional extension of DBCLRecord. When a Record
+// implements it, saveRecords inserts every queued new row with a single
+// call instead of one DBCLInsert per row (e.g. a multi-values INSERT or a
+// pq.CopyIn-backed COPY on postgres).
+type DBCLBulkInserter[Record DBCLRecord] interface {
+	DBCLBulkInsert(*sql.Tx, []Record) error
+}
+
+// DBCLBulkUpdater is an optional extension of DBCLRecord. When a Record
+// implements it, saveRecords updates every queued changed row with a
+// single call instead of one DBCLUpdate per row.
+type DBCLBulkUpdater[Record DBCLRecord] interface {
+	DBCLBulkUpdate(*sql.Tx, []Record) error
+}
+
+// DBCLBulkDeleter is an optional extension of DBCLRecord. When a Record
+// implements it, saveRecords deletes every queued removed id with a
+// single call instead of one DBCLDelete per id.
+type DBCLBulkDeleter interface {
+	DBCLBulkDelete(*sql.Tx, []int64) error
+}
+
+// DBCLBatchExister is an optional extension of DBCLRecord. When a Record
+// implements it, saveRecords resolves insert-vs-update for a whole batch
+// with a single `SELECT id FROM t WHERE id IN (...)`-style query instead
+// of one DBCLExists probe per row.
+type DBCLBatchExister interface {
+	DBCLExistsBatch(*sql.Tx, []int64) (map[int64]bool, error)
+}
+
+// index is the type-erased interface DBCL uses to maintain a named
+// secondary index without leaking its key type into DBCL's own type
+// parameters. It tracks ids, not record bodies, so lookups stay correct
+// regardless of whether a given id's body is currently resident in the
+// bounded LRU cache from WithMaxEntries: LookupBy/RangeBy reload evicted
+// bodies the same way GetRecord does.
+type index[Record DBCLRecord] interface {
+	name() string
+	insert(id int64, record Record)
+	remove(id int64)
+	rebuild()
+	lookup(key any) []int64
+	rangeLookup(low, high any) ([]int64, error)
+}
+
+// hashIndex is a map-backed index supporting exact-key lookups only.
+type hashIndex[Record DBCLRecord, K comparable] struct {
+	indexName string
+	extract   func(Record) K
+	byKey     map[K][]int64
+	keyOf     map[int64]K
+}
+
+func newHashIndex[Record DBCLRecord, K comparable](name string, extract func(Record) K) *hashIndex[Record, K] {
+	return &hashIndex[Record, K]{
+		indexName: name,
+		extract:   extract,
+		byKey:     make(map[K][]int64),
+		keyOf:     make(map[int64]K),
+	}
+}
+
+func (ix *hashIndex[Record, K]) name() string { return ix.indexName }
+
+func (ix *hashIndex[Record, K]) removeKeyedID(id int64) {
+	k, ok := ix.keyOf[id]
+	if !ok {
+		return
+	}
+	ids := ix.byKey[k]
+	for i, existing := range ids {
+		if existing == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(ix.byKey, k)
+	} else {
+		ix.byKey[k] = ids
+	}
+	delete(ix.keyOf, id)
+}
+
+func (ix *hashIndex[Record, K]) insert(id int64, record Record) {
+	ix.removeKeyedID(id)
+	k := ix.extract(record)
+	ix.byKey[k] = append(ix.byKey[k], id)
+	ix.keyOf[id] = k
+}
+
+func (ix *hashIndex[Record, K]) remove(id int64) {
+	ix.removeKeyedID(id)
+}
+
+func (ix *hashIndex[Record, K]) rebuild() {
+	ix.byKey = make(map[K][]int64)
+	ix.keyOf = make(map[int64]K)
+}
+
+func (ix *hashIndex[Record, K]) lookup(key any) []int64 {
+	k, ok := key.(K)
+	if !ok {
+		return nil
+	}
+	return ix.byKey[k]
+}
+
+func (ix *hashIndex[Record, K]) rangeLookup(any, any) ([]int64, error) {
+	return nil, fmt.Errorf("index %q only supports exact lookups; register it with WithOrderedIndex for RangeBy", ix.indexName)
+}
+
+// orderedIndex is a sorted-slice-backed index supporting both exact-key and
+// ranged lookups, at the cost of an O(log n) insertion-point search (and
+// O(n) shift) per distinct key instead of the hashIndex's O(1).
+type orderedIndex[Record DBCLRecord, K cmp.Ordered] struct {
+	indexName  string
+	extract    func(Record) K
+	byKey      map[K][]int64
+	keyOf      map[int64]K
+	sortedKeys []K
+}
+
+func newOrderedIndex[Record DBCLRecord, K cmp.Ordered](name string, extract func(Record) K) *orderedIndex[Record, K] {
+	return &orderedIndex[Record, K]{
+		indexName: name,
+		extract:   extract,
+		byKey:     make(map[K][]int64),
+		keyOf:     make(map[int64]K),
+	}
+}
+
+func (ix *orderedIndex[Record, K]) name() string { return ix.indexName }
+
+func (ix *orderedIndex[Record, K]) removeKeyedID(id int64) {
+	k, ok := ix.keyOf[id]
+	if !ok {
+		return
+	}
+	ids := ix.byKey[k]
+	for i, existing := range ids {
+		if existing == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(ix.byKey, k)
+		if i, found := slices.BinarySearch(ix.sortedKeys, k); found {
+			ix.sortedKeys = append(ix.sortedKeys[:i], ix.sortedKeys[i+1:]...)
+		}
+	} else {
+		ix.byKey[k] = ids
+	}
+	delete(ix.keyOf, id)
+}
+
+func (ix *orderedIndex[Record, K]) insert(id int64, record Record) {
+	ix.removeKeyedID(id)
+	k := ix.extract(record)
+	if _, ok := ix.byKey[k]; !ok {
+		i, _ := slices.BinarySearch(ix.sortedKeys, k)
+		ix.sortedKeys = append(ix.sortedKeys, k)
+		copy(ix.sortedKeys[i+1:], ix.sortedKeys[i:])
+		ix.sortedKeys[i] = k
+	}
+	ix.byKey[k] = append(ix.byKey[k], id)
+	ix.keyOf[id] = k
+}
+
+func (ix *orderedIndex[Record, K]) remove(id int64) {
+	ix.removeKeyedID(id)
+}
+
+func (ix *orderedIndex[Record, K]) rebuild() {
+	ix.byKey = make(map[K][]int64)
+	ix.keyOf = make(map[int64]K)
+	ix.sortedKeys = ix.sortedKeys[:0]
+}
+
+func (ix *orderedIndex[Record, K]) lookup(key any) []int64 {
+	k, ok := key.(K)
+	if !ok {
+		return nil
+	}
+	return ix.byKey[k]
+}
+
+func (ix *orderedIndex[Record, K]) rangeLookup(low, high any) ([]int64, error) {
+	lowK, ok := low.(K)
+	if !ok {
+		return nil, fmt.Errorf("low bound for index %q must be of the indexed key type", ix.indexName)
+	}
+	highK, ok := high.(K)
+	if !ok {
+		return nil, fmt.Errorf("high bound for index %q must be of the indexed key type", ix.indexName)
+	}
+
+	start, _ := slices.BinarySearch(ix.sortedKeys, lowK)
+
+	var ids []int64
+	for _, k := range ix.sortedKeys[start:] {
+		if k > highK {
+			break
+		}
+		ids = append(ids, ix.byKey[k]...)
+	}
+	return ids, nil
+}
+
+// Option configures a DBCL at construction time. See NewDBCL.
+type Option[Record DBCLRecord] func(*DBCL[Record])
+
+// WithMaxEntries bounds the number of record bodies kept in memory to n,
+// turning records into an LRU cache that lazily loads bodies from the
+// database on a miss instead of pinning the whole table in RAM. Entries
+// with pending writes are never evicted until saveRecords flushes them.
+// A non-positive value (the default) preserves the original behavior of
+// preloading and keeping every row resident.
+func WithMaxEntries[Record DBCLRecord](n int) Option[Record] {
+	return func(d *DBCL[Record]) {
+		d.maxEntries = n
+	}
+}
+
+// WithLogger replaces the default log.Printf-based logger with logger.
+// Use this to route DBCL's diagnostics through zap, zerolog, or any other
+// implementation of the Logger interface.
+func WithLogger[Record DBCLRecord](logger Logger) Option[Record] {
+	return func(d *DBCL[Record]) {
+		d.logger = logger
+	}
+}
+
+// WithJournalDir gives CloseContext a directory to serialize any writeCache
+// entries it could not flush before its context expired. On the next
+// NewDBCL, that journal is replayed into the database and removed before
+// loadRecords runs, so a graceful-but-interrupted shutdown does not lose
+// pending writes. Leaving this unset disables journaling entirely.
+func WithJournalDir[Record DBCLRecord](dir string) Option[Record] {
+	return func(d *DBCL[Record]) {
+		d.journalDir = dir
+	}
+}
+
+// WithIndex registers a named secondary index keyed by extract(record),
+// supporting exact-key lookups via LookupBy. Indexes are rebuilt from
+// scratch from the DB every time loadRecords runs, and are updated by
+// every insert, update and delete made through the DBCL in between.
+func WithIndex[Record DBCLRecord, K comparable](name string, extract func(Record) K) Option[Record] {
+	return func(d *DBCL[Record]) {
+		ix := newHashIndex[Record, K](name, extract)
+		d.indexes = append(d.indexes, ix)
+		d.indexByName[name] = ix
+	}
+}
+
+// WithOrderedIndex registers a named secondary index like WithIndex, but
+// additionally supports ranged lookups via RangeBy since its keys must be
+// ordered.
+func WithOrderedIndex[Record DBCLRecord, K cmp.Ordered](name string, extract func(Record) K) Option[Record] {
+	return func(d *DBCL[Record]) {
+		ix := newOrderedIndex[Record, K](name, extract)
+		d.indexes = append(d.indexes, ix)
+		d.indexByName[name] = ix
+	}
+}
+
 type DBCL[Record DBCLRecord] struct {
 	db           *sql.DB
 	ticker       *time.Ticker
@@ -31,22 +337,52 @@ type DBCL[Record DBCLRecord] struct {
 	nextRecordId int64
 	records      map[int64]Record
 	writeCache   map[int64][]Record
+	maxEntries   int
+	lruList      *list.List
+	lruElems     map[int64]*list.Element
+
+	logger     Logger
+	journalDir string
+
+	indexes     []index[Record]
+	indexByName map[string]index[Record]
+
+	insertsFlushed       atomic.Uint64
+	updatesFlushed       atomic.Uint64
+	deletesFlushed       atomic.Uint64
+	flushErrors          atomic.Uint64
+	flushDurationBuckets []atomic.Uint64
+	lruHits              atomic.Uint64
+	lruMisses            atomic.Uint64
+	lastFlushErrMtx      sync.Mutex
+	lastFlushErr         error
 }
 
-func NewDBCL[Record DBCLRecord](driverName, dataSourceName string, interval time.Duration) (*DBCL[Record], error) {
+func NewDBCL[Record DBCLRecord](driverName, dataSourceName string, interval time.Duration, opts ...Option[Record]) (*DBCL[Record], error) {
 	db, err := sql.Open(driverName, dataSourceName)
 	if err != nil {
 		return nil, err
 	}
 
 	s := &DBCL[Record]{
-		db:           db,
-		ticker:       time.NewTicker(interval),
-		stop:         make(chan bool),
-		keyRecords:   make([]int64, 0),
-		nextRecordId: 1,
-		records:      make(map[int64]Record),
-		writeCache:   make(map[int64][]Record),
+		db:                   db,
+		ticker:               time.NewTicker(interval),
+		stop:                 make(chan bool),
+		keyRecords:           make([]int64, 0),
+		nextRecordId:         1,
+		records:              make(map[int64]Record),
+		writeCache:           make(map[int64][]Record),
+		lruList:              list.New(),
+		lruElems:             make(map[int64]*list.Element),
+		logger:               stdLogger{},
+		indexByName:          make(map[string]index[Record]),
+		flushDurationBuckets: make([]atomic.Uint64, len(flushDurationBucketBoundsMs)+1),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.replayJournal(); err != nil {
+		s.logger.Errorf("Error replaying write-ahead journal: %v", err)
 	}
 	s.loadRecords()
 	s.wg.Add(1)
@@ -57,7 +393,7 @@ func NewDBCL[Record DBCLRecord](driverName, dataSourceName string, interval time
 			select {
 			case <-s.ticker.C:
 				if err := s.saveRecords(); err != nil {
-					log.Printf("Error saving records: %v", err)
+					s.logger.Errorf("Error saving records: %v", err)
 				}
 			case <-s.stop:
 				return
@@ -72,44 +408,191 @@ func (d *DBCL[Record]) loadRecords() error {
 	d.mtx.Lock()
 	defer d.mtx.Unlock()
 
-	var r Record
+	r := newBlankRecord[Record]()
+	d.keyRecords = make([]int64, 0)
+	d.records = make(map[int64]Record)
+	d.lruList = list.New()
+	d.lruElems = make(map[int64]*list.Element)
+	for _, idx := range d.indexes {
+		idx.rebuild()
+	}
+
+	// With no registered indexes, a bounded cache can skip loading bodies
+	// entirely and just probe for the set of known ids. Indexes need a
+	// body per row to extract their key from, so they force a full scan
+	// even when the cache itself is bounded; the bodies are simply not
+	// kept resident afterwards.
+	if d.maxEntries > 0 && len(d.indexes) == 0 {
+		rows, err := r.DBCLSelectIDs(d.db)
+		if err != nil {
+			return fmt.Errorf("error querying record ids: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return fmt.Errorf("error scanning record id: %w", err)
+			}
+
+			d.keyRecords = append(d.keyRecords, id)
+			d.nextRecordId = id + 1
+		}
+
+		return nil
+	}
+
 	rows, err := r.DBCLSelectAll(d.db)
 	if err != nil {
 		return fmt.Errorf("error querying records: %w", err)
 	}
 	defer rows.Close()
 
-	d.keyRecords = make([]int64, 0)
-	d.records = make(map[int64]Record)
-
 	for rows.Next() {
-		var record Record
+		record := newBlankRecord[Record]()
 		if err := record.DBCLScan(rows); err != nil {
 			return fmt.Errorf("error scanning record: %w", err)
 		}
 
-		d.records[record.DBCLGetId()] = record
-		d.keyRecords = append(d.keyRecords, record.DBCLGetId())
-		d.nextRecordId = record.DBCLGetId() + 1
+		id := record.DBCLGetId()
+		d.keyRecords = append(d.keyRecords, id)
+		d.nextRecordId = id + 1
+		for _, idx := range d.indexes {
+			idx.insert(id, record)
+		}
+
+		if d.maxEntries <= 0 {
+			d.records[id] = record
+		}
 	}
 
 	return nil
 }
 
+// lruTouch marks id as most-recently-used, adding it to the LRU if it is
+// not already tracked. A no-op when the cache is unbounded.
+func (d *DBCL[Record]) lruTouch(id int64) {
+	if d.maxEntries <= 0 {
+		return
+	}
+	if elem, ok := d.lruElems[id]; ok {
+		d.lruList.MoveToFront(elem)
+		return
+	}
+	d.lruElems[id] = d.lruList.PushFront(id)
+}
+
+// lruForget drops id from the LRU bookkeeping without touching d.records.
+func (d *DBCL[Record]) lruForget(id int64) {
+	if d.maxEntries <= 0 {
+		return
+	}
+	if elem, ok := d.lruElems[id]; ok {
+		d.lruList.Remove(elem)
+		delete(d.lruElems, id)
+	}
+}
+
+// lruEvict drops least-recently-used record bodies until d.records is back
+// within maxEntries, skipping any id with pending writeCache entries since
+// those must stay resident until saveRecords flushes them.
+func (d *DBCL[Record]) lruEvict() {
+	if d.maxEntries <= 0 {
+		return
+	}
+	for len(d.records) > d.maxEntries {
+		elem := d.lruList.Back()
+		evicted := false
+		for elem != nil {
+			id := elem.Value.(int64)
+			if _, pinned := d.writeCache[id]; pinned {
+				elem = elem.Prev()
+				continue
+			}
+			toRemove := elem
+			elem = nil
+			d.lruList.Remove(toRemove)
+			delete(d.lruElems, id)
+			delete(d.records, id)
+			evicted = true
+			break
+		}
+		if !evicted {
+			return
+		}
+	}
+}
+
+// fillMissing batch-loads any ids in the requested range that are not
+// currently cached, using a single ranged query per contiguous gap instead
+// of one round-trip per miss.
+func (d *DBCL[Record]) fillMissing(ids []int64) {
+	var r Record
+	for i := 0; i < len(ids); {
+		if _, ok := d.records[ids[i]]; ok {
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(ids) {
+			if _, ok := d.records[ids[j]]; ok {
+				break
+			}
+			j++
+		}
+
+		func() {
+			rows, err := r.DBCLSelectRange(d.db, ids[i], int64(j-i))
+			if err != nil {
+				return
+			}
+			defer rows.Close()
+
+			for rows.Next() {
+				record := newBlankRecord[Record]()
+				if err := record.DBCLScan(rows); err != nil {
+					return
+				}
+				d.records[record.DBCLGetId()] = record
+			}
+		}()
+
+		i = j
+	}
+}
+
+// coalesceWriteCacheEntry collapses the queued changes for a single id down
+// to one terminal state: a delete wins if a zero value appears anywhere in
+// the queue (it was deleted at some point since the last flush), otherwise
+// the last queued value wins.
+func coalesceWriteCacheEntry[Record DBCLRecord](recordChanges []Record) (record Record, isDelete bool) {
+	var zero Record
+	record = recordChanges[len(recordChanges)-1]
+	for _, recordChange := range recordChanges {
+		if any(recordChange) == any(zero) {
+			return zero, true
+		}
+	}
+	return record, false
+}
+
 func (d *DBCL[Record]) saveRecords() error {
+	start := time.Now()
+
 	d.mtx.Lock()
 	writeCache := d.writeCache
 	d.writeCache = make(map[int64][]Record)
 	d.mtx.Unlock()
 
-	tx, err := d.db.Begin()
-	if err != nil {
-		return fmt.Errorf("could not begin transaction: %w", err)
-	}
+	var tx *sql.Tx
+	var err error
 
 	defer func() {
 		rollbackChanges := func() {
-			tx.Rollback()
+			if tx != nil {
+				tx.Rollback()
+			}
 			d.mtx.Lock()
 			for id, recordChanges := range writeCache {
 				d.writeCache[id] = append(recordChanges, d.writeCache[id]...)
@@ -119,53 +602,243 @@ func (d *DBCL[Record]) saveRecords() error {
 
 		if p := recover(); p != nil {
 			rollbackChanges()
+			d.recordFlushDuration(time.Since(start))
+			d.setLastFlushError(err)
+			d.flushErrors.Add(1)
 			panic(p)
 		} else if err != nil {
 			rollbackChanges()
-		} else {
+		} else if tx != nil {
 			err = tx.Commit()
 		}
+
+		d.recordFlushDuration(time.Since(start))
+		d.setLastFlushError(err)
+		if err != nil {
+			d.flushErrors.Add(1)
+		}
 	}()
 
+	tx, err = d.db.Begin()
+	if err != nil {
+		err = fmt.Errorf("could not begin transaction: %w", err)
+		return err
+	}
+
+	var deletes []int64
+	var upserts []Record
 	for id, recordChanges := range writeCache {
-		for _, recordChange := range recordChanges {
-			var r, zero Record
-			if any(recordChange) == any(zero) {
-				_, err = r.DBCLDelete(tx, id)
+		record, isDelete := coalesceWriteCacheEntry[Record](recordChanges)
+		if isDelete {
+			deletes = append(deletes, id)
+		} else {
+			upserts = append(upserts, record)
+		}
+	}
+
+	var r Record
+
+	exists := make(map[int64]bool, len(upserts))
+	if len(upserts) > 0 {
+		if batchExister, ok := any(r).(DBCLBatchExister); ok {
+			ids := make([]int64, len(upserts))
+			for i, record := range upserts {
+				ids[i] = record.DBCLGetId()
+			}
+			exists, err = batchExister.DBCLExistsBatch(tx, ids)
+			if err != nil {
+				err = fmt.Errorf("error checking existence of %d records: %w", len(ids), err)
+				return err
+			}
+		} else {
+			for _, record := range upserts {
+				id := record.DBCLGetId()
+				exists[id], err = r.DBCLExists(tx, id)
 				if err != nil {
-					return fmt.Errorf("error deleting record with ID %v: %w", id, err)
+					err = fmt.Errorf("error checking existence of record with ID %v: %w", id, err)
+					return err
 				}
-				log.Printf("Deleted record with ID %v", id)
-			} else {
-				var exists bool
-				exists, err = r.DBCLExists(tx, id)
-				if err != nil {
-					return fmt.Errorf("error checking existence of record with ID %v: %w", id, err)
+			}
+		}
+	}
+
+	var inserts, updates []Record
+	for _, record := range upserts {
+		if exists[record.DBCLGetId()] {
+			updates = append(updates, record)
+		} else {
+			inserts = append(inserts, record)
+		}
+	}
+
+	if len(inserts) > 0 {
+		if bulkInserter, ok := any(r).(DBCLBulkInserter[Record]); ok {
+			if err = bulkInserter.DBCLBulkInsert(tx, inserts); err != nil {
+				err = fmt.Errorf("error bulk inserting %d records: %w", len(inserts), err)
+				return err
+			}
+			d.logger.Infof("Bulk inserted %d records", len(inserts))
+		} else {
+			for _, record := range inserts {
+				if _, err = r.DBCLInsert(tx, record); err != nil {
+					err = fmt.Errorf("error inserting record with ID %v: %w", record.DBCLGetId(), err)
+					return err
 				}
-				if exists {
-					_, err = r.DBCLUpdate(tx, recordChange)
-					if err != nil {
-						return fmt.Errorf("error updating record with ID %v: %w", id, err)
-					}
-					log.Printf("Updated record with ID %v", id)
-				} else {
-					_, err = r.DBCLInsert(tx, recordChange)
-					if err != nil {
-						return fmt.Errorf("error inserting record with ID %v: %w", id, err)
-					}
-					log.Printf("Inserted new record with ID %v", id)
+				d.logger.Debugf("Inserted new record with ID %v", record.DBCLGetId())
+			}
+		}
+		d.insertsFlushed.Add(uint64(len(inserts)))
+	}
+
+	if len(updates) > 0 {
+		if bulkUpdater, ok := any(r).(DBCLBulkUpdater[Record]); ok {
+			if err = bulkUpdater.DBCLBulkUpdate(tx, updates); err != nil {
+				err = fmt.Errorf("error bulk updating %d records: %w", len(updates), err)
+				return err
+			}
+			d.logger.Infof("Bulk updated %d records", len(updates))
+		} else {
+			for _, record := range updates {
+				if _, err = r.DBCLUpdate(tx, record); err != nil {
+					err = fmt.Errorf("error updating record with ID %v: %w", record.DBCLGetId(), err)
+					return err
 				}
+				d.logger.Debugf("Updated record with ID %v", record.DBCLGetId())
 			}
 		}
+		d.updatesFlushed.Add(uint64(len(updates)))
+	}
+
+	if len(deletes) > 0 {
+		if bulkDeleter, ok := any(r).(DBCLBulkDeleter); ok {
+			if err = bulkDeleter.DBCLBulkDelete(tx, deletes); err != nil {
+				err = fmt.Errorf("error bulk deleting %d records: %w", len(deletes), err)
+				return err
+			}
+			d.logger.Infof("Bulk deleted %d records", len(deletes))
+		} else {
+			for _, id := range deletes {
+				if _, err = r.DBCLDelete(tx, id); err != nil {
+					err = fmt.Errorf("error deleting record with ID %v: %w", id, err)
+					return err
+				}
+				d.logger.Debugf("Deleted record with ID %v", id)
+			}
+		}
+		d.deletesFlushed.Add(uint64(len(deletes)))
 	}
 
 	return err
 }
 
+// recordFlushDuration tallies elapsed into the matching histogram bucket
+// of flushDurationBucketBoundsMs, with an overflow bucket for anything
+// slower than the last bound.
+func (d *DBCL[Record]) recordFlushDuration(elapsed time.Duration) {
+	ms := elapsed.Milliseconds()
+	for i, bound := range flushDurationBucketBoundsMs {
+		if ms <= bound {
+			d.flushDurationBuckets[i].Add(1)
+			return
+		}
+	}
+	d.flushDurationBuckets[len(flushDurationBucketBoundsMs)].Add(1)
+}
+
+func (d *DBCL[Record]) setLastFlushError(err error) {
+	d.lastFlushErrMtx.Lock()
+	d.lastFlushErr = err
+	d.lastFlushErrMtx.Unlock()
+}
+
+func flushDurationBucketLabel(i int) string {
+	if i < len(flushDurationBucketBoundsMs) {
+		return fmt.Sprintf("<=%dms", flushDurationBucketBoundsMs[i])
+	}
+	return fmt.Sprintf(">%dms", flushDurationBucketBoundsMs[len(flushDurationBucketBoundsMs)-1])
+}
+
+// Stats is a point-in-time snapshot of the counters DBCL maintains about
+// its flush loop and LRU cache. See DBCL.Stats.
+type Stats struct {
+	InsertsFlushed       uint64
+	UpdatesFlushed       uint64
+	DeletesFlushed       uint64
+	FlushErrors          uint64
+	FlushDurationBuckets map[string]uint64
+	WriteCacheDepth      int
+	LRUHits              uint64
+	LRUMisses            uint64
+	LastFlushError       error
+}
+
+// Stats returns a snapshot of the counters accumulated since this DBCL was
+// created, giving callers an actionable way to observe latency spikes in
+// the ticker goroutine without forking the package.
+func (d *DBCL[Record]) Stats() Stats {
+	d.mtx.Lock()
+	writeCacheDepth := len(d.writeCache)
+	d.mtx.Unlock()
+
+	buckets := make(map[string]uint64, len(d.flushDurationBuckets))
+	for i := range d.flushDurationBuckets {
+		buckets[flushDurationBucketLabel(i)] = d.flushDurationBuckets[i].Load()
+	}
+
+	d.lastFlushErrMtx.Lock()
+	lastFlushErr := d.lastFlushErr
+	d.lastFlushErrMtx.Unlock()
+
+	return Stats{
+		InsertsFlushed:       d.insertsFlushed.Load(),
+		UpdatesFlushed:       d.updatesFlushed.Load(),
+		DeletesFlushed:       d.deletesFlushed.Load(),
+		FlushErrors:          d.flushErrors.Load(),
+		FlushDurationBuckets: buckets,
+		WriteCacheDepth:      writeCacheDepth,
+		LRUHits:              d.lruHits.Load(),
+		LRUMisses:            d.lruMisses.Load(),
+		LastFlushError:       lastFlushErr,
+	}
+}
+
 func (d *DBCL[Record]) GetRecord(id int64) Record {
 	d.mtx.Lock()
 	defer d.mtx.Unlock()
-	return d.records[id]
+
+	if record, ok := d.records[id]; ok {
+		d.lruHits.Add(1)
+		d.lruTouch(id)
+		return record
+	}
+	d.lruMisses.Add(1)
+
+	var zero Record
+	if d.maxEntries <= 0 || binarySearch(d.keyRecords, id) == -1 {
+		return zero
+	}
+
+	var r Record
+	rows, err := r.DBCLSelectByID(d.db, id)
+	if err != nil {
+		return zero
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return zero
+	}
+
+	record := newBlankRecord[Record]()
+	if err := record.DBCLScan(rows); err != nil {
+		return zero
+	}
+
+	d.records[id] = record
+	d.lruTouch(id)
+	d.lruEvict()
+
+	return record
 }
 
 func (d *DBCL[Record]) GetRecordsRange(offset, limit int64) []Record {
@@ -177,10 +850,24 @@ func (d *DBCL[Record]) GetRecordsRange(offset, limit int64) []Record {
 
 	if offset < keyRecordsLength {
 		cuttingLength := min(keyRecordsLength, offset+limit)
+		ids := d.keyRecords[offset:cuttingLength]
 
-		for _, id := range d.keyRecords[offset:cuttingLength] {
+		if d.maxEntries > 0 {
+			for _, id := range ids {
+				if _, ok := d.records[id]; ok {
+					d.lruHits.Add(1)
+				} else {
+					d.lruMisses.Add(1)
+				}
+			}
+			d.fillMissing(ids)
+		}
+
+		for _, id := range ids {
 			records = append(records, d.records[id])
+			d.lruTouch(id)
 		}
+		d.lruEvict()
 	}
 
 	return records
@@ -209,25 +896,33 @@ func (d *DBCL[Record]) modifyRecord(id int64, record Record) {
 	if id == 0 {
 		id = d.nextRecordId
 	}
-	_, recordExists := d.records[id]
+	// id may be known (present in keyRecords, i.e. a row that exists in the
+	// DB) without its body being resident in d.records: bounded mode only
+	// loads bodies on demand, and when registered indexes force a full scan
+	// at startup the scanned bodies are not kept. Insert-vs-update and delete must key off id being
+	// known, not off map residence, or a never-loaded id is silently
+	// treated as brand new.
+	i := binarySearch(d.keyRecords, id)
+	knownID := i != -1
 	var zero Record
 	if any(record) == any(zero) {
-		if recordExists {
-			i := binarySearch(d.keyRecords, id)
-			if i != -1 {
-				newLen := len(d.keyRecords) - 1
-				d.keyRecords[i] = d.keyRecords[newLen]
-				d.keyRecords = d.keyRecords[:newLen]
-				sort.Slice(d.keyRecords, func(i, j int) bool {
-					return d.keyRecords[i] < d.keyRecords[j]
-				})
-			}
+		if knownID {
+			newLen := len(d.keyRecords) - 1
+			d.keyRecords[i] = d.keyRecords[newLen]
+			d.keyRecords = d.keyRecords[:newLen]
+			sort.Slice(d.keyRecords, func(i, j int) bool {
+				return d.keyRecords[i] < d.keyRecords[j]
+			})
 			delete(d.records, id)
+			d.lruForget(id)
+			for _, idx := range d.indexes {
+				idx.remove(id)
+			}
 			d.writeCache[id] = append(d.writeCache[id], zero)
 		}
 	} else {
 		record.DBCLSetId(id)
-		if !recordExists {
+		if !knownID {
 			d.nextRecordId++
 			d.keyRecords = append(d.keyRecords, id)
 			sort.Slice(d.keyRecords, func(i, j int) bool {
@@ -235,10 +930,63 @@ func (d *DBCL[Record]) modifyRecord(id int64, record Record) {
 			})
 		}
 		d.records[id] = record
+		d.lruTouch(id)
+		for _, idx := range d.indexes {
+			idx.insert(id, record)
+		}
 		d.writeCache[id] = append(d.writeCache[id], record)
+		d.lruEvict()
 	}
 }
 
+// LookupBy returns every record whose extracted key for the named index
+// (registered via WithIndex or WithOrderedIndex) equals key, in id order.
+// An unregistered index name yields nil; a key of the wrong type yields an
+// empty slice.
+func (d *DBCL[Record]) LookupBy(indexName string, key any) []Record {
+	d.mtx.Lock()
+	idx, ok := d.indexByName[indexName]
+	if !ok {
+		d.mtx.Unlock()
+		return nil
+	}
+	ids := append([]int64(nil), idx.lookup(key)...)
+	d.mtx.Unlock()
+
+	return d.recordsForIds(ids)
+}
+
+// RangeBy returns every record whose extracted key for the named index
+// falls within [low, high]. indexName must have been registered with
+// WithOrderedIndex; any other index name returns an error.
+func (d *DBCL[Record]) RangeBy(indexName string, low, high any) ([]Record, error) {
+	d.mtx.Lock()
+	idx, ok := d.indexByName[indexName]
+	if !ok {
+		d.mtx.Unlock()
+		return nil, fmt.Errorf("no index named %q is registered", indexName)
+	}
+	ids, err := idx.rangeLookup(low, high)
+	d.mtx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.recordsForIds(ids), nil
+}
+
+// recordsForIds resolves ids to records in ascending id order, reloading
+// any body evicted from the bounded cache the same way GetRecord does.
+func (d *DBCL[Record]) recordsForIds(ids []int64) []Record {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	records := make([]Record, 0, len(ids))
+	for _, id := range ids {
+		records = append(records, d.GetRecord(id))
+	}
+	return records
+}
+
 func (d *DBCL[Record]) InsertRecord(record Record) {
 	d.modifyRecord(0, record)
 }
@@ -259,3 +1007,195 @@ func (d *DBCL[Record]) Close() error {
 	d.wg.Wait()
 	return d.db.Close()
 }
+
+// CloseContext stops the ticker goroutine like Close, then flushes whatever
+// is left in writeCache synchronously, retrying with backoff until ctx is
+// done. If the final flush still fails and WithJournalDir was given, the
+// remaining writeCache entries are serialized to a journal file so a future
+// NewDBCL can recover them instead of losing the writes.
+func (d *DBCL[Record]) CloseContext(ctx context.Context) error {
+	d.ticker.Stop()
+	close(d.stop)
+	d.wg.Wait()
+
+	if err := d.flushUntil(ctx); err != nil {
+		if d.journalDir == "" {
+			return fmt.Errorf("final flush failed and no journal directory is configured: %w", err)
+		}
+		if journalErr := d.journalPendingWrites(); journalErr != nil {
+			return fmt.Errorf("final flush failed (%v) and journaling pending writes also failed: %w", err, journalErr)
+		}
+		d.logger.Errorf("Final flush failed during shutdown, pending writes journaled for recovery: %v", err)
+	}
+
+	return d.db.Close()
+}
+
+// flushUntil retries saveRecords with exponential backoff until it succeeds
+// or ctx is done, whichever comes first.
+func (d *DBCL[Record]) flushUntil(ctx context.Context) error {
+	const maxBackoff = time.Second
+
+	backoff := 10 * time.Millisecond
+	for {
+		err := d.saveRecords()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// journalEntry is one line of the write-ahead journal file: a coalesced
+// writeCache change for a single id, serialized as JSON.
+type journalEntry struct {
+	ID     int64           `json:"id"`
+	Delete bool            `json:"delete"`
+	Record json.RawMessage `json:"record,omitempty"`
+}
+
+// journalPendingWrites drains whatever is left in writeCache to an
+// append-only journal file under d.journalDir.
+func (d *DBCL[Record]) journalPendingWrites() error {
+	d.mtx.Lock()
+	writeCache := d.writeCache
+	d.writeCache = make(map[int64][]Record)
+	d.mtx.Unlock()
+
+	if len(writeCache) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(d.journalDir, 0o755); err != nil {
+		return fmt.Errorf("could not create journal directory: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(d.journalDir, journalFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not open journal file: %w", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for id, recordChanges := range writeCache {
+		record, isDelete := coalesceWriteCacheEntry[Record](recordChanges)
+
+		entry := journalEntry{ID: id, Delete: isDelete}
+		if !isDelete {
+			body, err := json.Marshal(record)
+			if err != nil {
+				return fmt.Errorf("could not marshal record with ID %v for journal: %w", id, err)
+			}
+			entry.Record = body
+		}
+
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("could not write journal entry for record with ID %v: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// newBlankRecord allocates a fresh, non-nil Record so journal replay has
+// somewhere to unmarshal into. Record is conventionally a pointer type
+// (e.g. *Note), so this is just reflect.New of the pointed-to struct.
+func newBlankRecord[Record DBCLRecord]() Record {
+	var zero Record
+	if rt := reflect.TypeOf(zero); rt != nil && rt.Kind() == reflect.Ptr {
+		return reflect.New(rt.Elem()).Interface().(Record)
+	}
+	return zero
+}
+
+// replayJournal replays any entries left behind by a prior CloseContext
+// that could not flush cleanly, then removes the journal file. It is a
+// no-op when WithJournalDir was not given or no journal file exists.
+func (d *DBCL[Record]) replayJournal() error {
+	if d.journalDir == "" {
+		return nil
+	}
+
+	path := filepath.Join(d.journalDir, journalFileName)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("could not open journal file: %w", err)
+	}
+
+	var entries []journalEntry
+	dec := json.NewDecoder(f)
+	for {
+		var entry journalEntry
+		if err := dec.Decode(&entry); err != nil {
+			if err == io.EOF {
+				break
+			}
+			f.Close()
+			return fmt.Errorf("could not decode journal entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	f.Close()
+
+	if len(entries) == 0 {
+		return os.Remove(path)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not begin transaction for journal replay: %w", err)
+	}
+
+	var r Record
+	for _, entry := range entries {
+		if entry.Delete {
+			if _, err := r.DBCLDelete(tx, entry.ID); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("could not replay delete for record with ID %v: %w", entry.ID, err)
+			}
+			continue
+		}
+
+		record := newBlankRecord[Record]()
+		if err := json.Unmarshal(entry.Record, record); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not unmarshal journaled record with ID %v: %w", entry.ID, err)
+		}
+		record.DBCLSetId(entry.ID)
+
+		exists, err := r.DBCLExists(tx, entry.ID)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not check existence of journaled record with ID %v: %w", entry.ID, err)
+		}
+		if exists {
+			_, err = r.DBCLUpdate(tx, record)
+		} else {
+			_, err = r.DBCLInsert(tx, record)
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not replay write for record with ID %v: %w", entry.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit journal replay transaction: %w", err)
+	}
+
+	d.logger.Infof("Replayed %d journaled write(s) from %s", len(entries), path)
+
+	return os.Remove(path)
+}
@@ -1,7 +1,13 @@
 package dbcachinglayer
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -30,8 +36,20 @@ func (n *Note) DBCLSelectAll(db *sql.DB) (*sql.Rows, error) {
 	return db.Query("SELECT id, title, content FROM notes")
 }
 
+func (n *Note) DBCLSelectIDs(db *sql.DB) (*sql.Rows, error) {
+	return db.Query("SELECT id FROM notes ORDER BY id")
+}
+
+func (n *Note) DBCLSelectByID(db *sql.DB, id int64) (*sql.Rows, error) {
+	return db.Query("SELECT id, title, content FROM notes WHERE id = ?", id)
+}
+
+func (n *Note) DBCLSelectRange(db *sql.DB, startId, limit int64) (*sql.Rows, error) {
+	return db.Query("SELECT id, title, content FROM notes WHERE id >= ? ORDER BY id LIMIT ?", startId, limit)
+}
+
 func (n *Note) DBCLInsert(tx *sql.Tx, note DBCLRecord) (sql.Result, error) {
-	return tx.Exec("INSERT INTO notes (id, title, content) VALUES (?, ?)", note.(*Note).Id, note.(*Note).Title, note.(*Note).Content)
+	return tx.Exec("INSERT INTO notes (id, title, content) VALUES (?, ?, ?)", note.(*Note).Id, note.(*Note).Title, note.(*Note).Content)
 }
 
 func (n *Note) DBCLUpdate(tx *sql.Tx, note DBCLRecord) (sql.Result, error) {
@@ -115,3 +133,437 @@ func TestDBCachingLayer(t *testing.T) {
 		t.Fatalf("Expected record to be deleted")
 	}
 }
+
+func TestDBCachingLayerBoundedLRU(t *testing.T) {
+	dsn := "file::memory:?cache=shared"
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, title TEXT, content TEXT)")
+	if err != nil {
+		t.Fatalf("Error creating table: %v", err)
+	}
+
+	for id := int64(1); id <= 3; id++ {
+		if _, err := db.Exec("INSERT INTO notes (id, title, content) VALUES (?, ?, ?)", id, "Title", "Content"); err != nil {
+			t.Fatalf("Error seeding record %d: %v", id, err)
+		}
+	}
+
+	dbcl, err := NewDBCL[*Note]("sqlite3", dsn, time.Hour, WithMaxEntries[*Note](1))
+	if err != nil {
+		t.Fatalf("Error creating DBCL: %v", err)
+	}
+	defer dbcl.Close()
+
+	if len(dbcl.keyRecords) != 3 {
+		t.Fatalf("Expected 3 known ids, got %d", len(dbcl.keyRecords))
+	}
+	if len(dbcl.records) != 0 {
+		t.Fatalf("Expected no record bodies preloaded, got %d", len(dbcl.records))
+	}
+
+	record := dbcl.GetRecord(1)
+	if record == nil || record.Id != 1 {
+		t.Fatalf("Expected record 1 to be lazily loaded, got %v", record)
+	}
+	if len(dbcl.records) != 1 {
+		t.Fatalf("Expected exactly 1 record cached, got %d", len(dbcl.records))
+	}
+
+	record = dbcl.GetRecord(2)
+	if record == nil || record.Id != 2 {
+		t.Fatalf("Expected record 2 to be lazily loaded, got %v", record)
+	}
+	if len(dbcl.records) != 1 {
+		t.Fatalf("Expected eviction to keep the cache at capacity 1, got %d records", len(dbcl.records))
+	}
+	if _, ok := dbcl.records[1]; ok {
+		t.Fatalf("Expected record 1 to have been evicted in favor of record 2")
+	}
+}
+
+// BulkNote is a Note that also implements the optional bulk-write
+// interfaces, exercising the multi-row fast paths in saveRecords.
+type BulkNote struct {
+	Note
+}
+
+func NewBulkNote(id int64, title, content string) *BulkNote {
+	return &BulkNote{Note{id, title, content}}
+}
+
+func (n *BulkNote) DBCLNewRecord(args ...interface{}) DBCLRecord {
+	return NewBulkNote(args[0].(int64), args[1].(string), args[2].(string))
+}
+
+func (n *BulkNote) DBCLBulkInsert(tx *sql.Tx, records []*BulkNote) error {
+	if len(records) == 0 {
+		return nil
+	}
+	values := strings.TrimSuffix(strings.Repeat("(?, ?, ?), ", len(records)), ", ")
+	args := make([]interface{}, 0, len(records)*3)
+	for _, record := range records {
+		args = append(args, record.Id, record.Title, record.Content)
+	}
+	_, err := tx.Exec("INSERT INTO notes (id, title, content) VALUES "+values, args...)
+	return err
+}
+
+func (n *BulkNote) DBCLBulkUpdate(tx *sql.Tx, records []*BulkNote) error {
+	for _, record := range records {
+		if _, err := tx.Exec("UPDATE notes SET title = ?, content = ? WHERE id = ?", record.Title, record.Content, record.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *BulkNote) DBCLBulkDelete(tx *sql.Tx, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	_, err := tx.Exec("DELETE FROM notes WHERE id IN ("+placeholders+")", args...)
+	return err
+}
+
+func (n *BulkNote) DBCLExistsBatch(tx *sql.Tx, ids []int64) (map[int64]bool, error) {
+	result := make(map[int64]bool, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := tx.Query("SELECT id FROM notes WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		result[id] = true
+	}
+	return result, nil
+}
+
+func TestDBCachingLayerBulkWrites(t *testing.T) {
+	dsn := "file::memory:?cache=shared"
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, title TEXT, content TEXT)")
+	if err != nil {
+		t.Fatalf("Error creating table: %v", err)
+	}
+
+	dbcl, err := NewDBCL[*BulkNote]("sqlite3", dsn, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating DBCL: %v", err)
+	}
+	defer dbcl.Close()
+
+	dbcl.InsertRecord(NewBulkNote(0, "A", "1"))
+	dbcl.InsertRecord(NewBulkNote(0, "B", "2"))
+	if err := dbcl.saveRecords(); err != nil {
+		t.Fatalf("Error bulk inserting records: %v", err)
+	}
+
+	records := dbcl.GetRecordsRange(0, 10)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records after bulk insert, got %d", len(records))
+	}
+
+	dbcl.UpdateRecord(1, NewBulkNote(0, "A2", "1"))
+	if err := dbcl.saveRecords(); err != nil {
+		t.Fatalf("Error bulk updating records: %v", err)
+	}
+	record := dbcl.GetRecord(1)
+	if record == nil || record.Title != "A2" {
+		t.Fatalf("Expected record 1 title to be 'A2', got %v", record)
+	}
+
+	dbcl.DeleteRecord(2)
+	if err := dbcl.saveRecords(); err != nil {
+		t.Fatalf("Error bulk deleting records: %v", err)
+	}
+	if dbcl.GetRecord(2) != nil {
+		t.Fatalf("Expected record 2 to be deleted")
+	}
+
+	stats := dbcl.Stats()
+	if stats.InsertsFlushed != 2 {
+		t.Fatalf("Expected 2 inserts flushed, got %d", stats.InsertsFlushed)
+	}
+	if stats.UpdatesFlushed != 1 {
+		t.Fatalf("Expected 1 update flushed, got %d", stats.UpdatesFlushed)
+	}
+	if stats.DeletesFlushed != 1 {
+		t.Fatalf("Expected 1 delete flushed, got %d", stats.DeletesFlushed)
+	}
+	if stats.LastFlushError != nil {
+		t.Fatalf("Expected no flush error, got %v", stats.LastFlushError)
+	}
+}
+
+// captureLogger records every call made through the Logger interface so
+// tests can assert on what DBCL reports without scraping stdout.
+type captureLogger struct {
+	mtx  sync.Mutex
+	logs []string
+}
+
+func (l *captureLogger) Debugf(format string, args ...any) { l.add(format, args...) }
+func (l *captureLogger) Infof(format string, args ...any)  { l.add(format, args...) }
+func (l *captureLogger) Errorf(format string, args ...any) { l.add(format, args...) }
+
+func (l *captureLogger) add(format string, args ...any) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.logs = append(l.logs, fmt.Sprintf(format, args...))
+}
+
+func (l *captureLogger) count() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return len(l.logs)
+}
+
+func TestDBCachingLayerLoggerAndStats(t *testing.T) {
+	dsn := "file::memory:?cache=shared"
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, title TEXT, content TEXT)")
+	if err != nil {
+		t.Fatalf("Error creating table: %v", err)
+	}
+
+	logger := &captureLogger{}
+	dbcl, err := NewDBCL[*Note]("sqlite3", dsn, time.Hour, WithLogger[*Note](logger))
+	if err != nil {
+		t.Fatalf("Error creating DBCL: %v", err)
+	}
+	defer dbcl.Close()
+
+	dbcl.InsertRecord(NewNote(0, "Title", "Content"))
+	if err := dbcl.saveRecords(); err != nil {
+		t.Fatalf("Error saving records: %v", err)
+	}
+
+	if logger.count() == 0 {
+		t.Fatalf("Expected the injected logger to receive at least one message")
+	}
+
+	stats := dbcl.Stats()
+	if stats.InsertsFlushed != 1 {
+		t.Fatalf("Expected 1 insert flushed, got %d", stats.InsertsFlushed)
+	}
+	total := uint64(0)
+	for _, count := range stats.FlushDurationBuckets {
+		total += count
+	}
+	if total != 1 {
+		t.Fatalf("Expected exactly 1 flush recorded in the duration histogram, got %d", total)
+	}
+}
+
+func TestDBCachingLayerCloseContextJournalsAndRecovers(t *testing.T) {
+	dsn := "file::memory:?cache=shared"
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, title TEXT, content TEXT)")
+	if err != nil {
+		t.Fatalf("Error creating table: %v", err)
+	}
+
+	journalDir := t.TempDir()
+
+	dbcl, err := NewDBCL[*Note]("sqlite3", dsn, time.Hour, WithJournalDir[*Note](journalDir))
+	if err != nil {
+		t.Fatalf("Error creating DBCL: %v", err)
+	}
+
+	dbcl.InsertRecord(NewNote(0, "Title", "Content"))
+
+	// Close the underlying connection out from under the DBCL so the final
+	// flush inside CloseContext fails and falls back to journaling.
+	dbcl.db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := dbcl.CloseContext(ctx); err != nil {
+		t.Fatalf("Expected CloseContext to journal the pending write instead of failing, got %v", err)
+	}
+
+	journalPath := filepath.Join(journalDir, "writecache.journal")
+	if _, err := os.Stat(journalPath); err != nil {
+		t.Fatalf("Expected a journal file to be written: %v", err)
+	}
+
+	dbcl2, err := NewDBCL[*Note]("sqlite3", dsn, time.Hour, WithJournalDir[*Note](journalDir))
+	if err != nil {
+		t.Fatalf("Error creating recovering DBCL: %v", err)
+	}
+	defer dbcl2.Close()
+
+	record := dbcl2.GetRecord(1)
+	if record == nil || record.Title != "Title" {
+		t.Fatalf("Expected the journaled record to be replayed, got %v", record)
+	}
+
+	if _, err := os.Stat(journalPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected the journal file to be removed after a successful replay")
+	}
+}
+
+func TestDBCachingLayerIndexes(t *testing.T) {
+	dsn := "file::memory:?cache=shared"
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, title TEXT, content TEXT)")
+	if err != nil {
+		t.Fatalf("Error creating table: %v", err)
+	}
+
+	dbcl, err := NewDBCL[*Note]("sqlite3", dsn, time.Hour,
+		WithIndex[*Note]("byTitle", func(n *Note) string { return n.Title }),
+		WithOrderedIndex[*Note]("byContent", func(n *Note) string { return n.Content }),
+	)
+	if err != nil {
+		t.Fatalf("Error creating DBCL: %v", err)
+	}
+	defer dbcl.Close()
+
+	dbcl.InsertRecord(NewNote(0, "Alpha", "b"))
+	dbcl.InsertRecord(NewNote(0, "Beta", "a"))
+	dbcl.InsertRecord(NewNote(0, "Alpha", "c"))
+
+	byTitle := dbcl.LookupBy("byTitle", "Alpha")
+	if len(byTitle) != 2 {
+		t.Fatalf("Expected 2 records with title 'Alpha', got %d", len(byTitle))
+	}
+
+	byContent, err := dbcl.RangeBy("byContent", "a", "b")
+	if err != nil {
+		t.Fatalf("Error ranging by content: %v", err)
+	}
+	if len(byContent) != 2 {
+		t.Fatalf("Expected 2 records with content in [a, b], got %d", len(byContent))
+	}
+
+	dbcl.DeleteRecord(1)
+	byTitle = dbcl.LookupBy("byTitle", "Alpha")
+	if len(byTitle) != 1 {
+		t.Fatalf("Expected 1 record with title 'Alpha' after deletion, got %d", len(byTitle))
+	}
+
+	if _, err := dbcl.RangeBy("byTitle", "A", "Z"); err == nil {
+		t.Fatalf("Expected RangeBy on an exact-only index to return an error")
+	}
+}
+
+// TestDBCachingLayerBoundedIndexModifyNonResident guards against id
+// existence being decided by residence in the bounded cache instead of
+// membership in keyRecords: combining WithMaxEntries with WithIndex forces
+// loadRecords to scan every row to build the index without keeping any
+// body resident, so every pre-existing id starts out known but not cached.
+func TestDBCachingLayerBoundedIndexModifyNonResident(t *testing.T) {
+	dsn := "file::memory:?cache=shared"
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, title TEXT, content TEXT)")
+	if err != nil {
+		t.Fatalf("Error creating table: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO notes (id, title, content) VALUES (1, 'Alpha', 'a'), (2, 'Beta', 'b')"); err != nil {
+		t.Fatalf("Error seeding rows: %v", err)
+	}
+
+	dbcl, err := NewDBCL[*Note]("sqlite3", dsn, time.Hour,
+		WithMaxEntries[*Note](1),
+		WithIndex[*Note]("byTitle", func(n *Note) string { return n.Title }),
+	)
+	if err != nil {
+		t.Fatalf("Error creating DBCL: %v", err)
+	}
+	defer dbcl.Close()
+
+	if len(dbcl.records) != 0 {
+		t.Fatalf("Expected no bodies resident right after construction, got %d", len(dbcl.records))
+	}
+
+	// Update id 2 without ever having GetRecord-ed it first.
+	dbcl.UpdateRecord(2, NewNote(0, "Beta2", "b2"))
+	if err := dbcl.saveRecords(); err != nil {
+		t.Fatalf("Error saving update: %v", err)
+	}
+	if len(dbcl.keyRecords) != 2 {
+		t.Fatalf("Expected keyRecords to still have 2 entries after updating a non-resident id, got %d", len(dbcl.keyRecords))
+	}
+	var title string
+	if err := db.QueryRow("SELECT title FROM notes WHERE id = 2").Scan(&title); err != nil {
+		t.Fatalf("Error reading back updated row: %v", err)
+	}
+	if title != "Beta2" {
+		t.Fatalf("Expected updated title 'Beta2', got %q", title)
+	}
+
+	// Delete id 1 without ever having GetRecord-ed it first.
+	dbcl.DeleteRecord(1)
+	if err := dbcl.saveRecords(); err != nil {
+		t.Fatalf("Error saving delete: %v", err)
+	}
+	if len(dbcl.keyRecords) != 1 {
+		t.Fatalf("Expected keyRecords to have 1 entry after deleting a non-resident id, got %d", len(dbcl.keyRecords))
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM notes WHERE id = 1").Scan(&count); err != nil {
+		t.Fatalf("Error checking deletion: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("Expected row with id 1 to be deleted from the DB, got count %d", count)
+	}
+
+	byTitle := dbcl.LookupBy("byTitle", "Alpha")
+	if len(byTitle) != 0 {
+		t.Fatalf("Expected the deleted row to be gone from the index, got %d", len(byTitle))
+	}
+}